@@ -0,0 +1,220 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// changelogInsertMarker is the line that, when present in the changelog,
+// marks where new sections should be prepended.
+const changelogInsertMarker = "<!-- changelog-insert -->"
+
+// ChangelogConfig configures the persistent CHANGELOG.md maintained
+// alongside the GitHub release.
+type ChangelogConfig struct {
+	Path string `yaml:"path,omitempty"`
+
+	// Append is either "head" (the default, insert after
+	// changelogInsertMarker or at the top) or "tail" (append at the
+	// bottom of the file).
+	Append string `yaml:"append,omitempty"`
+
+	// Template, when set, overrides the built-in section renderer. It
+	// receives the same data and functions as ReleaseNoteGenerator.Template.
+	Template string `yaml:"template,omitempty"`
+
+	// Sections routes commits into named sections by matching a regex
+	// against the commit subject or body, instead of using
+	// ReleaseConfig.CommitCategories.
+	Sections map[string]string `yaml:"sections,omitempty"`
+}
+
+type changelogSection struct {
+	title string
+	re    *regexp.Regexp
+}
+
+func changelogSections(cfg ReleaseConfig) []changelogSection {
+	if len(cfg.Changelog.Sections) == 0 {
+		return nil
+	}
+
+	titles := make([]string, 0, len(cfg.Changelog.Sections))
+	for title := range cfg.Changelog.Sections {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+
+	sections := make([]changelogSection, 0, len(titles))
+	for _, title := range titles {
+		re, err := regexp.Compile(cfg.Changelog.Sections[title])
+		if err != nil {
+			continue
+		}
+		sections = append(sections, changelogSection{title: title, re: re})
+	}
+
+	return sections
+}
+
+// renderChangelog renders the Markdown section for a single version,
+// suitable for insertion into CHANGELOG.md by updateChangelog.
+func renderChangelog(proposal ReleaseProposal, config ReleaseConfig) []byte {
+	if t := config.Changelog.Template; t != "" {
+		if tmplText, err := loadTemplate(t); err == nil {
+			if out, err := renderReleaseNoteFromTemplate(tmplText, proposal, config); err == nil {
+				return out
+			}
+		}
+	}
+
+	var b strings.Builder
+	if proposal.Date != "" {
+		fmt.Fprintf(&b, "## %s - %s\n\n", proposal.Tag, proposal.Date)
+	} else {
+		fmt.Fprintf(&b, "## %s\n\n", proposal.Tag)
+	}
+
+	sections := changelogSections(config)
+	if len(sections) == 0 {
+		for _, c := range proposal.Commits {
+			fmt.Fprintf(&b, "- %s\n", c.ReleaseNote)
+		}
+		return []byte(b.String())
+	}
+
+	first := true
+	for _, s := range sections {
+		var matched []ReleaseCommit
+		for _, c := range proposal.Commits {
+			if s.re.MatchString(c.Subject) || s.re.MatchString(c.Body) {
+				matched = append(matched, c)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		if !first {
+			b.WriteString("\n")
+		}
+		first = false
+
+		fmt.Fprintf(&b, "### %s\n\n", s.title)
+		for _, c := range matched {
+			fmt.Fprintf(&b, "- %s\n", c.ReleaseNote)
+		}
+	}
+
+	return []byte(b.String())
+}
+
+// updateChangelog inserts section into the changelog at path, according to
+// appendMode ("head" or "tail", defaulting to "head"). If a section for the
+// same tag already exists, it is replaced rather than duplicated.
+func updateChangelog(path string, section []byte, appendMode string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+
+	lines = removeChangelogSection(lines, changelogSectionTag(section))
+	sectionLines := strings.Split(strings.TrimRight(string(section), "\n"), "\n")
+
+	var out []string
+	switch appendMode {
+	case "tail":
+		out = append(out, lines...)
+		if len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, sectionLines...)
+	default:
+		idx := -1
+		for i, l := range lines {
+			if strings.TrimSpace(l) == changelogInsertMarker {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			out = append(out, sectionLines...)
+			if len(lines) > 0 {
+				out = append(out, "")
+				out = append(out, lines...)
+			}
+		} else {
+			out = append(out, lines[:idx+1]...)
+			out = append(out, "")
+			out = append(out, sectionLines...)
+			if idx+1 < len(lines) {
+				out = append(out, "")
+				out = append(out, lines[idx+1:]...)
+			}
+		}
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(out, "\n")+"\n"), 0644)
+}
+
+// changelogSectionTag extracts the version heading (e.g. "v1.1.0" out of
+// "## v1.1.0 - 2021-05-01") from a rendered section.
+func changelogSectionTag(section []byte) string {
+	for _, line := range strings.Split(string(section), "\n") {
+		if strings.HasPrefix(line, "## ") {
+			fields := strings.Fields(strings.TrimPrefix(line, "## "))
+			if len(fields) > 0 {
+				return fields[0]
+			}
+		}
+	}
+	return ""
+}
+
+// removeChangelogSection drops the existing "## <tag> ..." section from
+// lines, if any, so it can be re-inserted without duplication.
+func removeChangelogSection(lines []string, tag string) []string {
+	if tag == "" {
+		return lines
+	}
+
+	var out []string
+	skipping := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "## ") {
+			fields := strings.Fields(strings.TrimPrefix(line, "## "))
+			skipping = len(fields) > 0 && fields[0] == tag
+		}
+		if skipping {
+			continue
+		}
+		out = append(out, line)
+	}
+
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+
+	return out
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateChangelog(t *testing.T) {
+	v1 := []byte("## v1.0.0 - 2021-05-01\n\n- Commit 1 message\n")
+	v2 := []byte("## v1.1.0 - 2021-06-01\n\n- Commit 2 message\n")
+	v2Regenerated := []byte("## v1.1.0 - 2021-06-01\n\n- Commit 2 message\n- Commit 3 message\n")
+
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+
+	// First-time creation.
+	require.NoError(t, updateChangelog(path, v1, "head"))
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(v1), string(got))
+
+	// Prepending against an existing changelog.
+	require.NoError(t, updateChangelog(path, v2, "head"))
+	got, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "## v1.1.0 - 2021-06-01\n\n- Commit 2 message\n\n## v1.0.0 - 2021-05-01\n\n- Commit 1 message\n", string(got))
+
+	// Idempotent regeneration for the same tag.
+	require.NoError(t, updateChangelog(path, v2Regenerated, "head"))
+	got, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "## v1.1.0 - 2021-06-01\n\n- Commit 2 message\n- Commit 3 message\n\n## v1.0.0 - 2021-05-01\n\n- Commit 1 message\n", string(got))
+}
+
+func TestUpdateChangelog_InsertMarker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	require.NoError(t, os.WriteFile(path, []byte("# Changelog\n\n"+changelogInsertMarker+"\n"), 0644))
+
+	v1 := []byte("## v1.0.0\n\n- Commit 1 message\n")
+	require.NoError(t, updateChangelog(path, v1, "head"))
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# Changelog\n\n"+changelogInsertMarker+"\n\n## v1.0.0\n\n- Commit 1 message\n", string(got))
+}
+
+func TestUpdateChangelog_Tail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	require.NoError(t, os.WriteFile(path, []byte("## v1.0.0\n\n- Commit 1 message\n"), 0644))
+
+	v2 := []byte("## v1.1.0\n\n- Commit 2 message\n")
+	require.NoError(t, updateChangelog(path, v2, "tail"))
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "## v1.0.0\n\n- Commit 1 message\n\n## v1.1.0\n\n- Commit 2 message\n", string(got))
+}
+
+func TestUpdateChangelog_DoesNotDropPrefixedTag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	require.NoError(t, os.WriteFile(path, []byte("## v1.1.0-beta - 2021-05-20\n\n- Commit 1 message\n"), 0644))
+
+	v2 := []byte("## v1.1.0 - 2021-06-01\n\n- Commit 2 message\n")
+	require.NoError(t, updateChangelog(path, v2, "head"))
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "## v1.1.0 - 2021-06-01\n\n- Commit 2 message\n\n## v1.1.0-beta - 2021-05-20\n\n- Commit 1 message\n", string(got))
+}
+
+func TestRenderChangelog(t *testing.T) {
+	proposal := ReleaseProposal{
+		Tag:  "v1.1.0",
+		Date: "2021-06-01",
+		Commits: []ReleaseCommit{
+			ReleaseCommit{Commit: Commit{Subject: "fix: correct typo"}, ReleaseNote: "correct typo"},
+			ReleaseCommit{Commit: Commit{Subject: "docs: update README"}, ReleaseNote: "update README"},
+		},
+	}
+
+	config := ReleaseConfig{
+		Changelog: ChangelogConfig{
+			Sections: map[string]string{
+				"Fixes":         `^fix:`,
+				"Documentation": `^docs:`,
+			},
+		},
+	}
+
+	got := renderChangelog(proposal, config)
+	expected := "## v1.1.0 - 2021-06-01\n\n### Documentation\n\n- update README\n\n### Fixes\n\n- correct typo\n"
+	assert.Equal(t, expected, string(got))
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// GitHubClient is the subset of the GitHub API used by this action. It
+// exists so the release flow can be tested without making real HTTP calls.
+type GitHubClient interface {
+	// GenerateReleaseNotes calls GitHub's "Generate release notes content"
+	// API (POST /repos/{owner}/{repo}/releases/generate-notes) and
+	// returns the generated body.
+	GenerateReleaseNotes(ctx context.Context, tagName, previousTagName, targetCommitish string) (string, error)
+}
+
+type githubClient struct {
+	owner, repo, token string
+	httpClient         *http.Client
+}
+
+func newGitHubClient(owner, repo, token string) *githubClient {
+	return &githubClient{
+		owner:      owner,
+		repo:       repo,
+		token:      token,
+		httpClient: http.DefaultClient,
+	}
+}
+
+func (c *githubClient) GenerateReleaseNotes(ctx context.Context, tagName, previousTagName, targetCommitish string) (string, error) {
+	payload, err := json.Marshal(struct {
+		TagName         string `json:"tag_name"`
+		PreviousTagName string `json:"previous_tag_name,omitempty"`
+		TargetCommitish string `json:"target_commitish,omitempty"`
+	}{
+		TagName:         tagName,
+		PreviousTagName: previousTagName,
+		TargetCommitish: targetCommitish,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/generate-notes", c.owner, c.repo)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("generate-notes request failed with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	return body.Body, nil
+}
+
+// splitRepository splits a GITHUB_REPOSITORY-style "owner/repo" string.
+func splitRepository(repository string) (owner, repo string) {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) != 2 {
+		return repository, ""
+	}
+	return parts[0], parts[1]
+}
+
+// spliceGitHubGeneratedNotes inserts generated into note at position
+// ("prepend", the default, "append" or "replace").
+func spliceGitHubGeneratedNotes(note []byte, generated, position string) []byte {
+	generated = strings.TrimSpace(generated)
+	if generated == "" {
+		return note
+	}
+
+	switch position {
+	case "append":
+		return []byte(strings.TrimRight(string(note), "\n") + "\n\n" + generated + "\n")
+	case "replace":
+		return []byte(generated + "\n")
+	default: // "prepend"
+		return []byte(generated + "\n\n" + strings.TrimLeft(string(note), "\n"))
+	}
+}
+
+// applyGitHubGeneratedNotes fetches GitHub's auto-generated release notes
+// and splices them into note when config.UseGitHubGeneratedNotes is set. On
+// any error it logs a warning and returns note unchanged so the release can
+// still ship.
+func applyGitHubGeneratedNotes(ctx context.Context, client GitHubClient, proposal ReleaseProposal, config ReleaseNoteGeneratorConfig, targetCommitish string, note []byte) []byte {
+	if !config.UseGitHubGeneratedNotes || client == nil {
+		return note
+	}
+
+	generated, err := client.GenerateReleaseNotes(ctx, proposal.Tag, proposal.PreTag, targetCommitish)
+	if err != nil {
+		log.Printf("warning: failed to fetch GitHub-generated release notes, falling back to the local renderer: %v", err)
+		return note
+	}
+
+	return spliceGitHubGeneratedNotes(note, generated, config.GitHubGeneratedNotesPosition)
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeGitHubClient struct {
+	body string
+	err  error
+}
+
+func (f *fakeGitHubClient) GenerateReleaseNotes(ctx context.Context, tagName, previousTagName, targetCommitish string) (string, error) {
+	return f.body, f.err
+}
+
+func TestApplyGitHubGeneratedNotes(t *testing.T) {
+	proposal := ReleaseProposal{Tag: "v1.1.0", PreTag: "v1.0.0"}
+	localNote := []byte("## v1.1.0\n\n- a local commit\n")
+
+	testcases := []struct {
+		name     string
+		client   GitHubClient
+		config   ReleaseNoteGeneratorConfig
+		expected string
+	}{
+		{
+			name:     "disabled leaves the note untouched",
+			client:   &fakeGitHubClient{body: "generated notes"},
+			config:   ReleaseNoteGeneratorConfig{},
+			expected: string(localNote),
+		},
+		{
+			name:     "prepend",
+			client:   &fakeGitHubClient{body: "generated notes"},
+			config:   ReleaseNoteGeneratorConfig{UseGitHubGeneratedNotes: true, GitHubGeneratedNotesPosition: "prepend"},
+			expected: "generated notes\n\n## v1.1.0\n\n- a local commit\n",
+		},
+		{
+			name:     "append",
+			client:   &fakeGitHubClient{body: "generated notes"},
+			config:   ReleaseNoteGeneratorConfig{UseGitHubGeneratedNotes: true, GitHubGeneratedNotesPosition: "append"},
+			expected: "## v1.1.0\n\n- a local commit\n\ngenerated notes\n",
+		},
+		{
+			name:     "replace",
+			client:   &fakeGitHubClient{body: "generated notes"},
+			config:   ReleaseNoteGeneratorConfig{UseGitHubGeneratedNotes: true, GitHubGeneratedNotesPosition: "replace"},
+			expected: "generated notes\n",
+		},
+		{
+			name:     "falls back to the local renderer on error",
+			client:   &fakeGitHubClient{err: fmt.Errorf("boom")},
+			config:   ReleaseNoteGeneratorConfig{UseGitHubGeneratedNotes: true, GitHubGeneratedNotesPosition: "replace"},
+			expected: string(localNote),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := applyGitHubGeneratedNotes(context.Background(), tc.client, proposal, tc.config, "main", localNote)
+			assert.Equal(t, tc.expected, string(got))
+		})
+	}
+}
+
+func TestSplitRepository(t *testing.T) {
+	owner, repo := splitRepository("kurochan/actions-gh-release")
+	assert.Equal(t, "kurochan", owner)
+	assert.Equal(t, "actions-gh-release", repo)
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const commitLogSeparator = "\x1f"
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	configPath := os.Getenv("INPUT_CONFIG_PATH")
+	if configPath == "" {
+		configPath = "release.yaml"
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", configPath, err)
+	}
+
+	config, err := parseReleaseConfig(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+
+	preTag := os.Getenv("INPUT_PREVIOUS_TAG")
+
+	commits, err := listCommits(preTag)
+	if err != nil {
+		return fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	releaseCommits := buildReleaseCommits(commits, *config)
+
+	tag := config.Tag
+	var bumpKind BumpKind
+	if tag == "" {
+		tag, bumpKind, err = nextVersion(preTag, releaseCommits, config.Versioning)
+		if err != nil {
+			return fmt.Errorf("failed to determine the next version: %w", err)
+		}
+	}
+
+	proposal := ReleaseProposal{
+		Tag:      tag,
+		PreTag:   preTag,
+		Name:     config.Name,
+		Commits:  releaseCommits,
+		BumpKind: bumpKind,
+	}
+
+	note := renderReleaseNote(proposal, *config)
+	if config.ReleaseNoteGenerator.UseGitHubGeneratedNotes {
+		owner, repo := splitRepository(os.Getenv("GITHUB_REPOSITORY"))
+		client := newGitHubClient(owner, repo, os.Getenv("GITHUB_TOKEN"))
+		note = applyGitHubGeneratedNotes(context.Background(), client, proposal, config.ReleaseNoteGenerator, os.Getenv("GITHUB_SHA"), note)
+	}
+	fmt.Println(string(note))
+
+	if changelogPath := os.Getenv("INPUT_CHANGELOG_PATH"); changelogPath != "" {
+		section := renderChangelog(proposal, *config)
+		if err := updateChangelog(changelogPath, section, config.Changelog.Append); err != nil {
+			return fmt.Errorf("failed to update changelog %s: %w", changelogPath, err)
+		}
+		if err := commitChangelog(changelogPath, proposal.Tag); err != nil {
+			return fmt.Errorf("failed to commit changelog %s: %w", changelogPath, err)
+		}
+	}
+
+	return nil
+}
+
+// commitChangelog commits and pushes the updated changelog using the
+// GitHub Actions checkout's existing credentials. It is a no-op when
+// regenerating the changelog for a tag left it byte-identical to what's
+// already committed, e.g. on a retried run.
+func commitChangelog(path, tag string) error {
+	add := exec.Command("git", "add", path)
+	add.Stdout = os.Stdout
+	add.Stderr = os.Stderr
+	if err := add.Run(); err != nil {
+		return err
+	}
+
+	diff := exec.Command("git", "diff", "--cached", "--quiet", "--", path)
+	if err := diff.Run(); err == nil {
+		return nil
+	} else if _, ok := err.(*exec.ExitError); !ok {
+		return err
+	}
+
+	commands := [][]string{
+		{"git", "-c", "user.name=github-actions[bot]", "-c", "user.email=github-actions[bot]@users.noreply.github.com", "commit", "-m", fmt.Sprintf("Update CHANGELOG.md for %s", tag)},
+		{"git", "push"},
+	}
+
+	for _, args := range commands {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listCommits returns the commits between preTag and HEAD, ordered oldest
+// first. When preTag is empty, the whole history up to HEAD is returned.
+func listCommits(preTag string) ([]Commit, error) {
+	rev := "HEAD"
+	if preTag != "" {
+		rev = fmt.Sprintf("%s..HEAD", preTag)
+	}
+
+	format := strings.Join([]string{"%H", "%s", "%b"}, commitLogSeparator)
+	cmd := exec.Command("git", "log", "--reverse", "--format="+format+"\x1e", rev)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, entry := range strings.Split(out.String(), "\x1e") {
+		entry = strings.TrimPrefix(entry, "\n")
+		if entry == "" {
+			continue
+		}
+		fields := strings.SplitN(entry, commitLogSeparator, 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Subject: fields[1],
+			Body:    strings.TrimSpace(fields[2]),
+		})
+	}
+
+	return commits, nil
+}
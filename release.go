@@ -0,0 +1,441 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+// defaultCommitParserPattern parses a Conventional Commits subject line
+// into type, scope, breaking-bang and title groups.
+const defaultCommitParserPattern = `^(?P<type>[a-zA-Z0-9_]+)(\((?P<scope>[^)]+)\))?(?P<bang>!)?:\s*(?P<title>.+)$`
+
+var releaseNoteBlockRegexp = regexp.MustCompile("(?s)```release-note\\s*\\n(.*?)\\n```")
+
+// breakingFooterRegexp matches a BREAKING CHANGE / BREAKING-CHANGE footer and
+// captures the description up to the next blank line or the end of the body.
+var breakingFooterRegexp = regexp.MustCompile(`(?ms)^BREAKING[- ]CHANGE:\s*(.+?)(\n\n|\z)`)
+
+// Commit represents a single commit fetched from the git history.
+type Commit struct {
+	Hash    string
+	Subject string
+	Body    string
+}
+
+// CommitParserConfig configures how commit subjects are parsed as
+// Conventional Commits.
+type CommitParserConfig struct {
+	// Pattern is a regular expression with named groups `type`, `scope`,
+	// `bang` and `title`. When empty, defaultCommitParserPattern is used.
+	Pattern string `yaml:"pattern,omitempty"`
+}
+
+func (c CommitParserConfig) pattern() string {
+	if c.Pattern == "" {
+		return defaultCommitParserPattern
+	}
+	return c.Pattern
+}
+
+// ReleaseCommitMatcherConfig specifies the rule to determine whether a given
+// commit matches or not.
+type ReleaseCommitMatcherConfig struct {
+	Contains []string `yaml:"contains,omitempty"`
+	Prefixes []string `yaml:"prefixes,omitempty"`
+}
+
+func (m ReleaseCommitMatcherConfig) isEmpty() bool {
+	return len(m.Contains) == 0 && len(m.Prefixes) == 0
+}
+
+func (m ReleaseCommitMatcherConfig) match(c Commit) bool {
+	for _, s := range m.Contains {
+		if strings.Contains(c.Subject, s) || strings.Contains(c.Body, s) {
+			return true
+		}
+	}
+	for _, p := range m.Prefixes {
+		if strings.HasPrefix(c.Subject, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseCommitCategoryConfig represents a single release note category.
+// A commit can be routed into a category either by the legacy
+// ReleaseCommitMatcherConfig or, when Types/Scopes/Breaking are set, by the
+// commit's Conventional Commits metadata.
+type ReleaseCommitCategoryConfig struct {
+	Id    string `yaml:"id,omitempty"`
+	Title string `yaml:"title,omitempty"`
+
+	// Types lists the Conventional Commits `type`s routed to this category
+	// (e.g. "feat"). Scopes further restricts by `scope`, and Breaking
+	// routes every breaking-change commit here regardless of its type.
+	Types    []string `yaml:"types,omitempty"`
+	Scopes   []string `yaml:"scopes,omitempty"`
+	Breaking bool     `yaml:"breaking,omitempty"`
+
+	ReleaseCommitMatcherConfig `yaml:",inline"`
+}
+
+func (c ReleaseCommitCategoryConfig) hasParserRules() bool {
+	return len(c.Types) > 0 || len(c.Scopes) > 0 || c.Breaking
+}
+
+func (c ReleaseCommitCategoryConfig) matches(rc ReleaseCommit) bool {
+	if c.hasParserRules() {
+		if c.Breaking {
+			return rc.IsBreaking
+		}
+		if len(c.Types) > 0 && !containsString(c.Types, rc.Type) {
+			return false
+		}
+		if len(c.Scopes) > 0 && !containsString(c.Scopes, rc.Scope) {
+			return false
+		}
+		return true
+	}
+	if !c.ReleaseCommitMatcherConfig.isEmpty() {
+		return c.ReleaseCommitMatcherConfig.match(rc.Commit)
+	}
+	// A category with no matcher and no parser rules acts as the catch-all.
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseNoteGeneratorConfig configures how the release note body is
+// rendered.
+type ReleaseNoteGeneratorConfig struct {
+	ShowCommitter       bool `yaml:"showCommitter,omitempty"`
+	UseReleaseNoteBlock bool `yaml:"useReleaseNoteBlock,omitempty"`
+
+	// Template, when set, overrides the built-in Markdown renderer with a
+	// text/template template. It may be an inline template string or a
+	// path to a template file.
+	Template string `yaml:"template,omitempty"`
+
+	// Substitutions applies each regex -> replacement pair to the
+	// rendered release note, e.g. to turn `#123` into a link.
+	Substitutions map[string]string `yaml:"substitutions,omitempty"`
+
+	// UseGitHubGeneratedNotes enables splicing GitHub's own "Generate
+	// release notes" output into the rendered note.
+	UseGitHubGeneratedNotes bool `yaml:"useGitHubGeneratedNotes,omitempty"`
+
+	// GitHubGeneratedNotesPosition is one of "prepend" (the default),
+	// "append" or "replace".
+	GitHubGeneratedNotesPosition string `yaml:"gitHubGeneratedNotesPosition,omitempty"`
+
+	// ShowType and ShowScope render each entry as
+	// `- **type(scope):** note` using the commit's Conventional Commits
+	// metadata.
+	ShowType  bool `yaml:"showType,omitempty"`
+	ShowScope bool `yaml:"showScope,omitempty"`
+}
+
+// ReleaseConfig is the whole configuration of this action, typically loaded
+// from a YAML file at the repository root.
+type ReleaseConfig struct {
+	Tag  string `yaml:"tag,omitempty"`
+	Name string `yaml:"name,omitempty"`
+
+	CommitInclude    ReleaseCommitMatcherConfig    `yaml:"commitInclude,omitempty"`
+	CommitExclude    ReleaseCommitMatcherConfig    `yaml:"commitExclude,omitempty"`
+	CommitCategories []ReleaseCommitCategoryConfig `yaml:"commitCategories,omitempty"`
+	CommitParser     CommitParserConfig            `yaml:"commitParser,omitempty"`
+	Versioning       VersioningConfig              `yaml:"versioning,omitempty"`
+	Changelog        ChangelogConfig               `yaml:"changelog,omitempty"`
+
+	ReleaseNoteGenerator ReleaseNoteGeneratorConfig `yaml:"releaseNoteGenerator,omitempty"`
+}
+
+// parseReleaseConfig parses the given YAML data into a ReleaseConfig,
+// assigning a default id to any category that doesn't specify one.
+func parseReleaseConfig(data []byte) (*ReleaseConfig, error) {
+	cfg := &ReleaseConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+
+	for i := range cfg.CommitCategories {
+		if cfg.CommitCategories[i].Id == "" {
+			cfg.CommitCategories[i].Id = fmt.Sprintf("_category_%d", i)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ReleaseCommit is a Commit enriched with the metadata needed to render it
+// in the release note.
+type ReleaseCommit struct {
+	Commit
+
+	CategoryName string
+
+	// Type, Scope and IsBreaking are populated from parsing the commit
+	// subject/body as a Conventional Commit. Type and Scope are left empty
+	// when the subject doesn't match the configured pattern; IsBreaking is
+	// still set when the body has a BREAKING CHANGE footer.
+	Type       string
+	Scope      string
+	IsBreaking bool
+
+	// BreakingDescription holds the text following a BREAKING CHANGE /
+	// BREAKING-CHANGE footer, when present.
+	BreakingDescription string
+
+	ReleaseNote string
+}
+
+// ReleaseProposal is the set of commits and metadata used to render and
+// publish a single release.
+type ReleaseProposal struct {
+	Tag     string
+	PreTag  string
+	Name    string
+	Commits []ReleaseCommit
+
+	// BumpKind is the semantic version bump level that produced Tag, set
+	// when Tag was derived by nextVersion rather than configured
+	// explicitly. It is surfaced to templates as {{.BumpKind}}.
+	BumpKind BumpKind
+
+	// Date is the release date, formatted by the caller (e.g. "2021-05-01").
+	Date string
+}
+
+// parseConventionalCommit attempts to parse subject as a Conventional
+// Commit using pattern. ok is false when subject doesn't match.
+func parseConventionalCommit(subject, pattern string) (typ, scope, title string, breaking, ok bool) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", "", "", false, false
+	}
+
+	m := re.FindStringSubmatch(subject)
+	if m == nil {
+		return "", "", "", false, false
+	}
+
+	groups := make(map[string]string, len(m))
+	for i, name := range re.SubexpNames() {
+		if name != "" && i < len(m) {
+			groups[name] = m[i]
+		}
+	}
+
+	return groups["type"], groups["scope"], groups["title"], groups["bang"] == "!", true
+}
+
+// extractBreakingChangeFooter returns the description following a
+// BREAKING CHANGE / BREAKING-CHANGE footer in body, if any.
+func extractBreakingChangeFooter(body string) (string, bool) {
+	m := breakingFooterRegexp.FindStringSubmatch(body)
+	if m == nil {
+		return "", false
+	}
+	return strings.TrimSpace(m[1]), true
+}
+
+// extractReleaseNoteBlock returns the content of a ```release-note fenced
+// block in body, if any.
+func extractReleaseNoteBlock(body string) (string, bool) {
+	m := releaseNoteBlockRegexp.FindStringSubmatch(body)
+	if m == nil {
+		return "", false
+	}
+	return strings.TrimSpace(m[1]), true
+}
+
+// buildReleaseCommits filters commits according to config.CommitInclude and
+// config.CommitExclude, then enriches and categorizes the remaining ones.
+func buildReleaseCommits(commits []Commit, config ReleaseConfig) []ReleaseCommit {
+	out := make([]ReleaseCommit, 0, len(commits))
+
+	for _, c := range commits {
+		if !config.CommitInclude.isEmpty() && !config.CommitInclude.match(c) {
+			continue
+		}
+		if !config.CommitExclude.isEmpty() && config.CommitExclude.match(c) {
+			continue
+		}
+
+		rc := ReleaseCommit{Commit: c}
+
+		typ, scope, title, bang, ok := parseConventionalCommit(c.Subject, config.CommitParser.pattern())
+		breakingDesc, hasBreakingFooter := extractBreakingChangeFooter(c.Body)
+		if ok {
+			rc.Type = typ
+			rc.Scope = scope
+		}
+		rc.IsBreaking = bang || hasBreakingFooter
+		rc.BreakingDescription = breakingDesc
+
+		rc.CategoryName = categorizeReleaseCommit(rc, config.CommitCategories)
+
+		if note, found := extractReleaseNoteBlock(c.Body); found {
+			rc.ReleaseNote = note
+		} else if ok && title != "" {
+			rc.ReleaseNote = title
+		} else {
+			rc.ReleaseNote = c.Subject
+		}
+
+		out = append(out, rc)
+	}
+
+	return out
+}
+
+func categorizeReleaseCommit(rc ReleaseCommit, categories []ReleaseCommitCategoryConfig) string {
+	for _, cat := range categories {
+		if cat.matches(rc) {
+			return cat.Id
+		}
+	}
+	return ""
+}
+
+// renderReleaseNote renders the Markdown body of a release note for the
+// given proposal. When config.ReleaseNoteGenerator.Template is set, it is
+// used instead of the built-in renderer; any error from parsing or
+// executing it falls back to the built-in renderer so the release can
+// still ship.
+func renderReleaseNote(proposal ReleaseProposal, config ReleaseConfig) []byte {
+	if t := config.ReleaseNoteGenerator.Template; t != "" {
+		if tmplText, err := loadTemplate(t); err == nil {
+			if out, err := renderReleaseNoteFromTemplate(tmplText, proposal, config); err == nil {
+				return applySubstitutions(out, config.ReleaseNoteGenerator.Substitutions)
+			}
+		}
+	}
+
+	return applySubstitutions(renderDefaultReleaseNote(proposal, config), config.ReleaseNoteGenerator.Substitutions)
+}
+
+// renderDefaultReleaseNote is the built-in Markdown renderer, used when no
+// custom template is configured.
+func renderDefaultReleaseNote(proposal ReleaseProposal, config ReleaseConfig) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## %s\n\n", proposal.Tag)
+	b.WriteString(renderBreakingChangesSection(proposal.Commits))
+
+	if len(config.CommitCategories) == 0 {
+		for _, c := range proposal.Commits {
+			fmt.Fprintf(&b, "- %s\n", formatReleaseNoteEntry(c, config.ReleaseNoteGenerator))
+		}
+		return []byte(b.String())
+	}
+
+	first := true
+	for _, cat := range config.CommitCategories {
+		var matched []ReleaseCommit
+		for _, c := range proposal.Commits {
+			if c.CategoryName == cat.Id {
+				matched = append(matched, c)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		if !first {
+			b.WriteString("\n")
+		}
+		first = false
+
+		fmt.Fprintf(&b, "### %s\n\n", cat.Title)
+		for _, c := range matched {
+			fmt.Fprintf(&b, "- %s\n", formatReleaseNoteEntry(c, config.ReleaseNoteGenerator))
+		}
+	}
+
+	return []byte(b.String())
+}
+
+// renderBreakingChangesSection renders a "### ⚠ BREAKING CHANGES" block
+// listing every breaking commit, or an empty string when there are none.
+func renderBreakingChangesSection(commits []ReleaseCommit) string {
+	var breaking []ReleaseCommit
+	for _, c := range commits {
+		if c.IsBreaking {
+			breaking = append(breaking, c)
+		}
+	}
+	if len(breaking) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("### ⚠ BREAKING CHANGES\n\n")
+	for _, c := range breaking {
+		fmt.Fprintf(&b, "- `%s` %s", shortSHA(c.Hash), c.Subject)
+		if c.BreakingDescription != "" {
+			fmt.Fprintf(&b, ": %s", c.BreakingDescription)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatReleaseNoteEntry renders a single commit's bullet point, optionally
+// prefixed with its Conventional Commits type/scope and suffixed with its
+// PR reference and author, e.g. `**feat(api):** add X (#123) @user`.
+func formatReleaseNoteEntry(c ReleaseCommit, config ReleaseNoteGeneratorConfig) string {
+	var prefix string
+	switch {
+	case config.ShowType && c.Type != "" && config.ShowScope && c.Scope != "":
+		prefix = fmt.Sprintf("**%s(%s):** ", c.Type, c.Scope)
+	case config.ShowType && c.Type != "":
+		prefix = fmt.Sprintf("**%s:** ", c.Type)
+	case config.ShowScope && c.Scope != "":
+		prefix = fmt.Sprintf("**(%s):** ", c.Scope)
+	}
+
+	line := prefix + c.ReleaseNote
+
+	if pr := prLink(c); pr != "" {
+		line += " (" + pr + ")"
+	}
+	if config.ShowCommitter {
+		if author := authorLink(c); author != "" {
+			line += " " + author
+		}
+	}
+
+	return line
+}
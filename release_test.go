@@ -15,7 +15,6 @@
 package main
 
 import (
-	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,9 +29,9 @@ func TestParseReleaseConfig(t *testing.T) {
 		expectedErr error
 	}{
 		{
-			name:        "empty config",
-			configFile:  "testdata/empty-config.txt",
-			expectedErr: fmt.Errorf("tag must be specified"),
+			name:       "empty config",
+			configFile: "testdata/empty-config.txt",
+			expected:   &ReleaseConfig{},
 		},
 		{
 			name:       "valid config",
@@ -226,6 +225,103 @@ func TestBuildReleaseCommits(t *testing.T) {
 	}
 }
 
+func TestBuildReleaseCommits_CommitParser(t *testing.T) {
+	config := ReleaseConfig{
+		Tag: "v1.1.0",
+		CommitCategories: []ReleaseCommitCategoryConfig{
+			ReleaseCommitCategoryConfig{
+				Id:       "breaking-change",
+				Title:    "Breaking Changes",
+				Breaking: true,
+			},
+			ReleaseCommitCategoryConfig{
+				Id:    "new-feature",
+				Title: "New Features",
+				Types: []string{"feat"},
+			},
+			ReleaseCommitCategoryConfig{
+				Id:    "bug-fix",
+				Title: "Bug Fixes",
+				Types: []string{"fix"},
+			},
+			ReleaseCommitCategoryConfig{
+				Id:    "internal-change",
+				Title: "Internal Changes",
+			},
+		},
+	}
+
+	testcases := []struct {
+		name     string
+		commit   Commit
+		expected ReleaseCommit
+	}{
+		{
+			name: "breaking change via bang and scope",
+			commit: Commit{
+				Subject: "feat(api)!: remove legacy endpoint",
+			},
+			expected: ReleaseCommit{
+				Commit:       Commit{Subject: "feat(api)!: remove legacy endpoint"},
+				CategoryName: "breaking-change",
+				Type:         "feat",
+				Scope:        "api",
+				IsBreaking:   true,
+				ReleaseNote:  "remove legacy endpoint",
+			},
+		},
+		{
+			name: "plain fix",
+			commit: Commit{
+				Subject: "fix: correct off-by-one error",
+			},
+			expected: ReleaseCommit{
+				Commit:       Commit{Subject: "fix: correct off-by-one error"},
+				CategoryName: "bug-fix",
+				Type:         "fix",
+				ReleaseNote:  "correct off-by-one error",
+			},
+		},
+		{
+			name: "unparseable subject falls back to legacy category",
+			commit: Commit{
+				Subject: "Update README",
+			},
+			expected: ReleaseCommit{
+				Commit:       Commit{Subject: "Update README"},
+				CategoryName: "internal-change",
+				ReleaseNote:  "Update README",
+			},
+		},
+		{
+			name: "breaking change footer",
+			commit: Commit{
+				Subject: "refactor: simplify config loading",
+				Body:    "BREAKING CHANGE: the `config` field was renamed to `commitParser`\n\nSigned-off-by: someone",
+			},
+			expected: ReleaseCommit{
+				Commit: Commit{
+					Subject: "refactor: simplify config loading",
+					Body:    "BREAKING CHANGE: the `config` field was renamed to `commitParser`\n\nSigned-off-by: someone",
+				},
+				CategoryName:        "breaking-change",
+				Type:                "refactor",
+				IsBreaking:          true,
+				BreakingDescription: "the `config` field was renamed to `commitParser`",
+				ReleaseNote:         "simplify config loading",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := buildReleaseCommits([]Commit{tc.commit}, config)
+			require.Len(t, got, 1)
+			assert.Equal(t, tc.expected, got[0])
+		})
+	}
+}
+
 func TestRenderReleaseNote(t *testing.T) {
 	testcases := []struct {
 		name     string
@@ -334,6 +430,45 @@ func TestRenderReleaseNote(t *testing.T) {
 			},
 			expected: "testdata/has-category-release-note.txt",
 		},
+		{
+			name: "breaking changes",
+			proposal: ReleaseProposal{
+				Tag: "v1.2.0",
+				Commits: []ReleaseCommit{
+					ReleaseCommit{
+						Commit: Commit{
+							Hash:    "abcdef1234567",
+							Subject: "feat(api)!: remove legacy endpoint",
+						},
+						CategoryName:        "breaking-change",
+						Type:                "feat",
+						Scope:               "api",
+						IsBreaking:          true,
+						BreakingDescription: "the /v1/users endpoint has been removed",
+						ReleaseNote:         "remove legacy endpoint",
+					},
+					ReleaseCommit{
+						Commit: Commit{
+							Subject: "fix: correct off-by-one error",
+						},
+						CategoryName: "bug-fix",
+						Type:         "fix",
+						ReleaseNote:  "correct off-by-one error",
+					},
+				},
+			},
+			config: ReleaseConfig{
+				CommitCategories: []ReleaseCommitCategoryConfig{
+					ReleaseCommitCategoryConfig{Id: "breaking-change", Title: "Breaking Changes", Breaking: true},
+					ReleaseCommitCategoryConfig{Id: "bug-fix", Title: "Bug Fixes", Types: []string{"fix"}},
+				},
+				ReleaseNoteGenerator: ReleaseNoteGeneratorConfig{
+					ShowType:  true,
+					ShowScope: true,
+				},
+			},
+			expected: "testdata/breaking-release-note.txt",
+		},
 	}
 
 	for _, tc := range testcases {
@@ -347,3 +482,56 @@ func TestRenderReleaseNote(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderReleaseNote_Template(t *testing.T) {
+	proposal := ReleaseProposal{
+		Tag:    "v0.2.0",
+		PreTag: "v0.1.0",
+		Commits: []ReleaseCommit{
+			ReleaseCommit{
+				Commit:       Commit{Subject: "Commit 1 message (#42)"},
+				CategoryName: "notable-change",
+				ReleaseNote:  "Commit 1 message (#42)",
+			},
+		},
+	}
+
+	config := ReleaseConfig{
+		CommitCategories: []ReleaseCommitCategoryConfig{
+			ReleaseCommitCategoryConfig{Id: "notable-change", Title: "Notable Changes"},
+		},
+		ReleaseNoteGenerator: ReleaseNoteGeneratorConfig{
+			Template: "{{.Tag}} (from {{.PreTag}}){{range .Categories}}\n## {{.Title}}\n{{range .Commits}}- {{.ReleaseNote}} {{prLink .}}\n{{end}}{{end}}",
+			Substitutions: map[string]string{
+				`#(\d+)`: "[#$1](https://github.com/kurochan/actions-gh-release/issues/$1)",
+			},
+		},
+	}
+
+	got := renderReleaseNote(proposal, config)
+
+	expected := "v0.2.0 (from v0.1.0)\n## Notable Changes\n- Commit 1 message ([#42](https://github.com/kurochan/actions-gh-release/issues/42)) [#42](https://github.com/kurochan/actions-gh-release/issues/42)\n"
+	assert.Equal(t, expected, string(got))
+}
+
+func TestRenderReleaseNote_TemplateFallsBackOnError(t *testing.T) {
+	proposal := ReleaseProposal{
+		Tag: "v0.2.0",
+		Commits: []ReleaseCommit{
+			ReleaseCommit{
+				Commit:      Commit{Subject: "Commit 1 message"},
+				ReleaseNote: "Commit 1 message",
+			},
+		},
+	}
+
+	config := ReleaseConfig{
+		ReleaseNoteGenerator: ReleaseNoteGeneratorConfig{
+			Template: "{{.Tag",
+		},
+	}
+
+	got := renderReleaseNote(proposal, config)
+
+	assert.Equal(t, string(renderDefaultReleaseNote(proposal, config)), string(got))
+}
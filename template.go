@@ -0,0 +1,192 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+var (
+	prNumberRegexp = regexp.MustCompile(`#(\d+)`)
+	mentionRegexp  = regexp.MustCompile(`@([\w-]+)`)
+)
+
+// CategoryGroup is a single category of commits, ready to be rendered by a
+// release note template.
+type CategoryGroup struct {
+	Id      string
+	Title   string
+	Commits []ReleaseCommit
+}
+
+// releaseNoteData is the value passed to a user-supplied release note
+// template.
+type releaseNoteData struct {
+	Tag    string
+	PreTag string
+	Name   string
+	Date   string
+
+	Categories    []CategoryGroup
+	Uncategorized []ReleaseCommit
+	Breaking      []ReleaseCommit
+
+	BumpKind BumpKind
+
+	Config ReleaseConfig
+}
+
+// buildReleaseNoteData groups proposal.Commits by category, in the order
+// categories are declared in config, skipping categories with no commits.
+func buildReleaseNoteData(proposal ReleaseProposal, config ReleaseConfig) releaseNoteData {
+	data := releaseNoteData{
+		Tag:      proposal.Tag,
+		PreTag:   proposal.PreTag,
+		Name:     proposal.Name,
+		Date:     proposal.Date,
+		BumpKind: proposal.BumpKind,
+		Config:   config,
+	}
+
+	known := make(map[string]bool, len(config.CommitCategories))
+	for _, cat := range config.CommitCategories {
+		known[cat.Id] = true
+
+		var commits []ReleaseCommit
+		for _, c := range proposal.Commits {
+			if c.CategoryName == cat.Id {
+				commits = append(commits, c)
+			}
+		}
+		if len(commits) == 0 {
+			continue
+		}
+		data.Categories = append(data.Categories, CategoryGroup{
+			Id:      cat.Id,
+			Title:   cat.Title,
+			Commits: commits,
+		})
+	}
+
+	for _, c := range proposal.Commits {
+		if c.IsBreaking {
+			data.Breaking = append(data.Breaking, c)
+		}
+		if len(config.CommitCategories) == 0 || !known[c.CategoryName] {
+			data.Uncategorized = append(data.Uncategorized, c)
+		}
+	}
+
+	return data
+}
+
+func templateFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"shortSHA":   shortSHA,
+		"authorLink": authorLink,
+		"prLink":     prLink,
+		"join": func(sep string, items []string) string {
+			return strings.Join(items, sep)
+		},
+		"upper":     strings.ToUpper,
+		"title":     strings.Title,
+		"trimSpace": strings.TrimSpace,
+	}
+}
+
+func shortSHA(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// authorLink returns the first `@name` token found in the commit's body, or
+// an empty string. Any `@` counts, so an e-mail address in the body (as in
+// a `Co-authored-by:` trailer) yields its domain, not a username.
+func authorLink(rc ReleaseCommit) string {
+	m := mentionRegexp.FindStringSubmatch(rc.Body)
+	if m == nil {
+		return ""
+	}
+	return "@" + m[1]
+}
+
+// prLink returns the first `#123`-style reference found in the commit
+// subject, or an empty string.
+func prLink(rc ReleaseCommit) string {
+	m := prNumberRegexp.FindStringSubmatch(rc.Subject)
+	if m == nil {
+		return ""
+	}
+	return "#" + m[1]
+}
+
+// loadTemplate returns the template text for t, which may either be an
+// inline template or a path to a template file.
+func loadTemplate(t string) (string, error) {
+	if data, err := os.ReadFile(t); err == nil {
+		return string(data), nil
+	}
+	return t, nil
+}
+
+// renderReleaseNoteFromTemplate renders proposal through the user-supplied
+// template text.
+func renderReleaseNoteFromTemplate(tmplText string, proposal ReleaseProposal, config ReleaseConfig) ([]byte, error) {
+	tmpl, err := template.New("release-note").Funcs(templateFuncMap()).Parse(tmplText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse release note template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, buildReleaseNoteData(proposal, config)); err != nil {
+		return nil, fmt.Errorf("failed to render release note template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// applySubstitutions applies each regex -> replacement pair in subs to note.
+// Keys are sorted lexicographically before being applied so the result is
+// deterministic regardless of map iteration order.
+func applySubstitutions(note []byte, subs map[string]string) []byte {
+	if len(subs) == 0 {
+		return note
+	}
+
+	patterns := make([]string, 0, len(subs))
+	for pattern := range subs {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	out := string(note)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		out = re.ReplaceAllString(out, subs[pattern])
+	}
+
+	return []byte(out)
+}
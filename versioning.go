@@ -0,0 +1,161 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BumpKind identifies which part of a semantic version was incremented.
+type BumpKind string
+
+const (
+	BumpNone  BumpKind = "none"
+	BumpPatch BumpKind = "patch"
+	BumpMinor BumpKind = "minor"
+	BumpMajor BumpKind = "major"
+)
+
+var bumpRank = map[BumpKind]int{
+	BumpNone:  0,
+	BumpPatch: 1,
+	BumpMinor: 2,
+	BumpMajor: 3,
+}
+
+// VersioningConfig configures how the next tag is derived from the commits
+// since PreTag, used when ReleaseConfig.Tag is left empty.
+type VersioningConfig struct {
+	// UpdateMajor lists the commit types that bump the major version.
+	// A commit with IsBreaking set always bumps major, regardless of
+	// this list.
+	UpdateMajor []string `yaml:"updateMajor,omitempty"`
+	UpdateMinor []string `yaml:"updateMinor,omitempty"`
+	UpdatePatch []string `yaml:"updatePatch,omitempty"`
+
+	// IgnoreUnknown, when false (the default), makes nextVersion fail on
+	// a commit whose type doesn't match any of the lists above.
+	IgnoreUnknown bool `yaml:"ignoreUnknown,omitempty"`
+
+	// TagPattern is a fmt-style pattern with three `%d` verbs for
+	// major, minor and patch. Defaults to "v%d.%d.%d".
+	TagPattern string `yaml:"tagPattern,omitempty"`
+}
+
+var defaultUpdateMinor = []string{"feat"}
+var defaultUpdatePatch = []string{"fix", "perf", "refactor", "build", "ci", "chore", "docs", "style", "test"}
+
+func (c VersioningConfig) tagPattern() string {
+	if c.TagPattern == "" {
+		return "v%d.%d.%d"
+	}
+	return c.TagPattern
+}
+
+// nextVersion computes the next tag from the commits since preTag,
+// returning the rendered tag and the bump level that produced it.
+func nextVersion(preTag string, commits []ReleaseCommit, cfg VersioningConfig) (string, BumpKind, error) {
+	major, minor, patch, err := parseSemverTag(preTag, cfg.tagPattern())
+	if err != nil {
+		return "", BumpNone, err
+	}
+
+	bump := BumpNone
+	for _, c := range commits {
+		kind, known := classifyBump(c, cfg)
+		if !known {
+			if cfg.IgnoreUnknown {
+				continue
+			}
+			return "", BumpNone, fmt.Errorf("commit %q has type %q, which doesn't match any versioning rule", c.Subject, c.Type)
+		}
+		if bumpRank[kind] > bumpRank[bump] {
+			bump = kind
+		}
+	}
+
+	switch bump {
+	case BumpMajor:
+		major, minor, patch = major+1, 0, 0
+	case BumpMinor:
+		minor, patch = minor+1, 0
+	case BumpPatch:
+		patch = patch + 1
+	}
+
+	return fmt.Sprintf(cfg.tagPattern(), major, minor, patch), bump, nil
+}
+
+// classifyBump returns the bump level for c and whether it could be
+// classified at all.
+func classifyBump(c ReleaseCommit, cfg VersioningConfig) (BumpKind, bool) {
+	if c.IsBreaking {
+		return BumpMajor, true
+	}
+	if containsString(cfg.UpdateMajor, c.Type) {
+		return BumpMajor, true
+	}
+
+	updateMinor := cfg.UpdateMinor
+	if updateMinor == nil {
+		updateMinor = defaultUpdateMinor
+	}
+	if containsString(updateMinor, c.Type) {
+		return BumpMinor, true
+	}
+
+	updatePatch := cfg.UpdatePatch
+	if updatePatch == nil {
+		updatePatch = defaultUpdatePatch
+	}
+	if containsString(updatePatch, c.Type) {
+		return BumpPatch, true
+	}
+
+	return BumpNone, false
+}
+
+// parseSemverTag parses the major.minor.patch components out of tag
+// according to pattern, ignoring any pre-release/metadata suffix. An empty
+// tag (no previous release yet) parses to 0.0.0.
+func parseSemverTag(tag, pattern string) (major, minor, patch int, err error) {
+	if tag == "" {
+		return 0, 0, 0, nil
+	}
+
+	if i := strings.IndexAny(tag, "-+"); i >= 0 {
+		tag = tag[:i]
+	}
+
+	m := tagParseRegexp(pattern).FindStringSubmatch(tag)
+	if m == nil {
+		return 0, 0, 0, fmt.Errorf("tag %q doesn't match pattern %q", tag, pattern)
+	}
+
+	major, _ = strconv.Atoi(m[1])
+	minor, _ = strconv.Atoi(m[2])
+	patch, _ = strconv.Atoi(m[3])
+
+	return major, minor, patch, nil
+}
+
+func tagParseRegexp(pattern string) *regexp.Regexp {
+	escaped := regexp.QuoteMeta(pattern)
+	escaped = strings.ReplaceAll(escaped, `%d`, `(\d+)`)
+	return regexp.MustCompile("^" + escaped)
+}
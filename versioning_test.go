@@ -0,0 +1,113 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextVersion(t *testing.T) {
+	defaultConfig := VersioningConfig{}
+
+	testcases := []struct {
+		name        string
+		preTag      string
+		commits     []ReleaseCommit
+		config      VersioningConfig
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:    "initial release with no preTag",
+			preTag:  "",
+			commits: []ReleaseCommit{{Type: "feat"}},
+			config:  defaultConfig,
+		},
+		{
+			name:   "pure patch release",
+			preTag: "v1.2.3",
+			commits: []ReleaseCommit{
+				{Type: "fix"},
+				{Type: "docs"},
+			},
+			config: defaultConfig,
+		},
+		{
+			name:   "feat triggers minor",
+			preTag: "v1.2.3",
+			commits: []ReleaseCommit{
+				{Type: "fix"},
+				{Type: "feat"},
+			},
+			config: defaultConfig,
+		},
+		{
+			name:   "breaking bang triggers major",
+			preTag: "v1.2.3",
+			commits: []ReleaseCommit{
+				{Type: "feat"},
+				{Type: "feat", IsBreaking: true},
+			},
+			config: defaultConfig,
+		},
+		{
+			name:   "BREAKING CHANGE footer triggers major",
+			preTag: "v1.2.3",
+			commits: []ReleaseCommit{
+				{Type: "fix", IsBreaking: true, BreakingDescription: "removed flag"},
+			},
+			config: defaultConfig,
+		},
+		{
+			name:   "unknown type is rejected by default",
+			preTag: "v1.2.3",
+			commits: []ReleaseCommit{
+				{Type: "wip"},
+			},
+			config:      defaultConfig,
+			expectedErr: fmt.Errorf(`commit "" has type "wip", which doesn't match any versioning rule`),
+		},
+		{
+			name:   "unknown type is ignored when configured",
+			preTag: "v1.2.3",
+			commits: []ReleaseCommit{
+				{Type: "wip"},
+			},
+			config: VersioningConfig{IgnoreUnknown: true},
+		},
+	}
+
+	expectedTags := map[string]string{
+		"initial release with no preTag":          "v0.1.0",
+		"pure patch release":                      "v1.2.4",
+		"feat triggers minor":                     "v1.3.0",
+		"breaking bang triggers major":            "v2.0.0",
+		"BREAKING CHANGE footer triggers major":   "v2.0.0",
+		"unknown type is ignored when configured": "v1.2.3",
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tag, _, err := nextVersion(tc.preTag, tc.commits, tc.config)
+			assert.Equal(t, tc.expectedErr, err)
+			if tc.expectedErr == nil {
+				assert.Equal(t, expectedTags[tc.name], tag)
+			}
+		})
+	}
+}